@@ -0,0 +1,141 @@
+// Package accesskey implements durable per-user access-key/secret pairs,
+// stored in DynamoDB, as an alternative to the one-hour federation tokens
+// vended by the main vendor handler. A key lets a CI system upload traces
+// without repeating the interactive OAuth WebSocket exchange every run.
+package accesskey
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// keyIDBytes and secretBytes follow AWS's own access-key sizing: a short
+// opaque identifier suitable for use as a DynamoDB hash key, and a secret
+// long enough to resist brute force when used as a SigV4 signing key.
+const (
+	keyIDBytes  = 8
+	secretBytes = 32
+)
+
+// Key is a durable access-key/secret pair, scoped to the IAM policy and
+// upload key prefix it was issued for.
+type Key struct {
+	KeyID        string
+	Secret       string
+	Login        string
+	PolicyArn    string
+	UploadPrefix string
+	CreatedAt    time.Time
+}
+
+func randomToken(n int, encoding *base32.Encoding) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	if encoding != nil {
+		return encoding.EncodeToString(buf), nil
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// New generates a fresh Key for login, scoped to policyArn and
+// uploadPrefix. It does not persist the key; callers store it with a Store.
+func New(login, policyArn, uploadPrefix string) (*Key, error) {
+	keyID, err := randomToken(keyIDBytes, base32.StdEncoding.WithPadding(base32.NoPadding))
+	if err != nil {
+		return nil, fmt.Errorf("generating key id: %w", err)
+	}
+	secret, err := randomToken(secretBytes, nil)
+	if err != nil {
+		return nil, fmt.Errorf("generating secret: %w", err)
+	}
+	return &Key{
+		KeyID:        keyID,
+		Secret:       secret,
+		Login:        login,
+		PolicyArn:    policyArn,
+		UploadPrefix: uploadPrefix,
+		CreatedAt:    time.Now(),
+	}, nil
+}
+
+// Store persists Keys in DynamoDB, keyed by KeyID.
+type Store struct {
+	client *dynamodb.Client
+	table  string
+}
+
+func NewStore(client *dynamodb.Client, table string) *Store {
+	return &Store{client: client, table: table}
+}
+
+// Put records key in the table, keyed by its KeyID.
+func (s *Store) Put(ctx context.Context, key *Key) error {
+	item, err := attributevalue.MarshalMap(key)
+	if err != nil {
+		return fmt.Errorf("marshaling access key: %w", err)
+	}
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item:      item,
+	})
+	return err
+}
+
+// Get looks up the Key for keyID, or returns nil if no such key exists.
+func (s *Store) Get(ctx context.Context, keyID string) (*Key, error) {
+	out, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.table),
+		Key: map[string]types.AttributeValue{
+			"KeyID": &types.AttributeValueMemberS{Value: keyID},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.Item == nil {
+		return nil, nil
+	}
+	var key Key
+	if err := attributevalue.UnmarshalMap(out.Item, &key); err != nil {
+		return nil, fmt.Errorf("unmarshaling access key: %w", err)
+	}
+	return &key, nil
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// DeriveSigningKey computes the SigV4 signing key for a stored secret,
+// following the same AWS4-HMAC-SHA256 key-derivation chain AWS uses for a
+// real AWS_SECRET_ACCESS_KEY. This lets a presigning proxy validate
+// SigV4-signed uploads against a Key's secret exactly as it would against
+// a real AWS secret key.
+func DeriveSigningKey(secret, dateStamp, region, service string) []byte {
+	kDate := hmacSHA256([]byte("AWS4"+secret), []byte(dateStamp))
+	kRegion := hmacSHA256(kDate, []byte(region))
+	kService := hmacSHA256(kRegion, []byte(service))
+	return hmacSHA256(kService, []byte("aws4_request"))
+}
+
+// Sign returns the hex-encoded SigV4 signature of stringToSign under
+// signingKey.
+func Sign(stringToSign string, signingKey []byte) string {
+	return hex.EncodeToString(hmacSHA256(signingKey, []byte(stringToSign)))
+}
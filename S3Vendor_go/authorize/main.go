@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"github.com/Keno/BugReporting/S3Vendor_go/accesskey"
+)
+
+var errorLogger = log.New(os.Stderr, "ERROR ", log.Llongfile)
+
+var accessKeyTable = os.Getenv("ACCESS_KEY_TABLE")
+
+// maxClockSkew bounds how far a request's signed timestamp may drift from
+// the current time before it is rejected as expired, mirroring the ~15
+// minute window AWS itself enforces on SigV4 requests.
+const maxClockSkew = 15 * time.Minute
+
+// amzDateLayout is the ISO 8601 basic timestamp format SigV4 embeds as the
+// second line of StringToSign (e.g. "20150830T123600Z").
+const amzDateLayout = "20060102T150405Z"
+
+// AuthorizeRequest is the request a presigning proxy sends to validate a
+// SigV4-signed upload made with a durable access key: the AccessKeyId from
+// the request's Authorization header/credential scope, the canonicalized
+// StringToSign the proxy reconstructed, the request's Signature, and the
+// date/region/service from that same credential scope.
+type AuthorizeRequest struct {
+	AccessKeyId  string
+	DateStamp    string
+	Region       string
+	Service      string
+	StringToSign string
+	Signature    string
+}
+
+// AuthorizeResponse tells the proxy whether the signature is valid and, if
+// so, the scope it should additionally enforce on the underlying S3 call.
+type AuthorizeResponse struct {
+	Authorized   bool
+	PolicyArn    string
+	UploadPrefix string
+}
+
+func authorize(ctx context.Context, authReq AuthorizeRequest) (AuthorizeResponse, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		errorLogger.Println(err.Error())
+		return AuthorizeResponse{}, err
+	}
+	store := accesskey.NewStore(dynamodb.NewFromConfig(cfg), accessKeyTable)
+
+	key, err := store.Get(ctx, authReq.AccessKeyId)
+	if err != nil {
+		errorLogger.Println(err.Error())
+		return AuthorizeResponse{}, err
+	}
+	if key == nil {
+		return AuthorizeResponse{Authorized: false}, nil
+	}
+
+	signingKey := accesskey.DeriveSigningKey(key.Secret, authReq.DateStamp, authReq.Region, authReq.Service)
+	expected := accesskey.Sign(authReq.StringToSign, signingKey)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(authReq.Signature)) != 1 {
+		return AuthorizeResponse{Authorized: false}, nil
+	}
+
+	// The signature covers the entire StringToSign, including its embedded
+	// request timestamp, so a captured-and-replayed tuple can't move this
+	// timestamp forward without invalidating the signature above. Without
+	// this check, though, that same capture would authorize forever.
+	requestTime, err := requestTimestampFromStringToSign(authReq.StringToSign)
+	if err != nil {
+		errorLogger.Println(err.Error())
+		return AuthorizeResponse{Authorized: false}, nil
+	}
+	if !withinFreshnessWindow(requestTime, time.Now()) {
+		return AuthorizeResponse{Authorized: false}, nil
+	}
+
+	return AuthorizeResponse{
+		Authorized:   true,
+		PolicyArn:    key.PolicyArn,
+		UploadPrefix: key.UploadPrefix,
+	}, nil
+}
+
+// requestTimestampFromStringToSign extracts the request timestamp (the
+// second line of a SigV4 StringToSign) so its freshness can be checked.
+func requestTimestampFromStringToSign(stringToSign string) (time.Time, error) {
+	lines := strings.SplitN(stringToSign, "\n", 3)
+	if len(lines) < 2 {
+		return time.Time{}, errors.New("StringToSign is missing the request timestamp line")
+	}
+	t, err := time.Parse(amzDateLayout, lines[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing request timestamp: %w", err)
+	}
+	return t, nil
+}
+
+// withinFreshnessWindow reports whether requestTime is within maxClockSkew
+// of now, in either direction.
+func withinFreshnessWindow(requestTime, now time.Time) bool {
+	skew := now.Sub(requestTime)
+	if skew < 0 {
+		skew = -skew
+	}
+	return skew <= maxClockSkew
+}
+
+// httpAuthorize adapts authorize for deployment behind API Gateway, where
+// the presigning proxy POSTs an AuthorizeRequest body and reads back JSON.
+func httpAuthorize(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var authReq AuthorizeRequest
+	if err := json.Unmarshal([]byte(req.Body), &authReq); err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusBadRequest, Body: http.StatusText(http.StatusBadRequest)}, nil
+	}
+	resp, err := authorize(context.Background(), authReq)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError, Body: http.StatusText(http.StatusInternalServerError)}, nil
+	}
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusInternalServerError, Body: http.StatusText(http.StatusInternalServerError)}, nil
+	}
+	return events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: string(body)}, nil
+}
+
+func main() {
+	lambda.Start(httpAuthorize)
+}
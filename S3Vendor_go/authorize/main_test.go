@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRequestTimestampFromStringToSign(t *testing.T) {
+	stringToSign := "AWS4-HMAC-SHA256\n" +
+		"20150830T123600Z\n" +
+		"20150830/us-east-1/iam/aws4_request\n" +
+		"f536975d06c0309214f805bb90ccff089219ecd68b2577efef23edd43b7e1a59"
+
+	got, err := requestTimestampFromStringToSign(stringToSign)
+	if err != nil {
+		t.Fatalf("requestTimestampFromStringToSign() error = %v", err)
+	}
+	want := time.Date(2015, time.August, 30, 12, 36, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("requestTimestampFromStringToSign() = %v, want %v", got, want)
+	}
+}
+
+func TestRequestTimestampFromStringToSignMalformed(t *testing.T) {
+	if _, err := requestTimestampFromStringToSign("AWS4-HMAC-SHA256"); err == nil {
+		t.Error("requestTimestampFromStringToSign() = nil error, want error for a StringToSign with no timestamp line")
+	}
+	if _, err := requestTimestampFromStringToSign("AWS4-HMAC-SHA256\nnot-a-timestamp\nscope\nhash"); err == nil {
+		t.Error("requestTimestampFromStringToSign() = nil error, want error for an unparseable timestamp")
+	}
+}
+
+func TestWithinFreshnessWindow(t *testing.T) {
+	now := time.Date(2015, time.August, 30, 12, 36, 0, 0, time.UTC)
+
+	cases := []struct {
+		name        string
+		requestTime time.Time
+		want        bool
+	}{
+		{"exact match", now, true},
+		{"10 minutes old", now.Add(-10 * time.Minute), true},
+		{"10 minutes in the future", now.Add(10 * time.Minute), true},
+		{"20 minutes old", now.Add(-20 * time.Minute), false},
+		{"20 minutes in the future", now.Add(20 * time.Minute), false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := withinFreshnessWindow(c.requestTime, now); got != c.want {
+				t.Errorf("withinFreshnessWindow(%v, %v) = %v, want %v", c.requestTime, now, got, c.want)
+			}
+		})
+	}
+}
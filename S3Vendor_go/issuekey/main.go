@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/google/go-github/github"
+
+	"golang.org/x/oauth2"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"github.com/Keno/BugReporting/S3Vendor_go/accesskey"
+)
+
+var debugLogger = log.New(os.Stderr, "DEBUG ", log.Llongfile)
+var errorLogger = log.New(os.Stderr, "ERROR ", log.Llongfile)
+
+var accessKeyPolicyArn = "arn:aws:iam::873569884612:policy/julialang-dumps-upload"
+var accessKeyTable = os.Getenv("ACCESS_KEY_TABLE")
+
+// issueKey authenticates a GitHub user via OAuth code exchange, the same as
+// vendor, but instead of a one-hour federation token it mints a durable
+// access-key/secret pair scoped to that user's report prefix and stores it
+// in DynamoDB. A presigning proxy validates uploads against the stored
+// secret via the authorize handler, so CI callers only need to do the OAuth
+// exchange once to obtain a key, not on every upload.
+func issueKey(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	code := req.QueryStringParameters["code"]
+	if code == "" {
+		return clientError(400)
+	}
+	ctx := context.Background()
+	conf := &oauth2.Config{
+		ClientID:     "Iv1.c29a629771fe63c4",
+		ClientSecret: os.Getenv("CLIENT_SECRET"),
+		Scopes:       []string{""},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   "https://github.com/login/oauth/authorize",
+			TokenURL:  "https://github.com/login/oauth/access_token",
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}
+	token, err := conf.Exchange(ctx, code)
+	if err != nil {
+		return serverError(err)
+	}
+	client := github.NewClient(oauth2.NewClient(ctx, conf.TokenSource(ctx, token)))
+	user, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return serverError(err)
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return serverError(err)
+	}
+	store := accesskey.NewStore(dynamodb.NewFromConfig(cfg), accessKeyTable)
+
+	uploadPrefix := fmt.Sprintf("reports/%s-", user.GetLogin())
+	key, err := accesskey.New(user.GetLogin(), accessKeyPolicyArn, uploadPrefix)
+	if err != nil {
+		return serverError(err)
+	}
+	if err := store.Put(ctx, key); err != nil {
+		return serverError(err)
+	}
+
+	debugLogger.Println(fmt.Sprintf("Issued access key %s for %s", key.KeyID, user.GetLogin()))
+
+	responseData, err := json.Marshal(struct {
+		ACCESS_KEY_ID     string
+		ACCESS_KEY_SECRET string
+		UPLOAD_PREFIX     string
+	}{
+		ACCESS_KEY_ID:     key.KeyID,
+		ACCESS_KEY_SECRET: key.Secret,
+		UPLOAD_PREFIX:     key.UploadPrefix,
+	})
+	if err != nil {
+		return serverError(err)
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       string(responseData),
+	}, nil
+}
+
+// Add a helper for handling errors. This logs any error to os.Stderr
+// and returns a 500 Internal Server Error response that the AWS API
+// Gateway understands.
+func serverError(err error) (events.APIGatewayProxyResponse, error) {
+	errorLogger.Println(err.Error())
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusInternalServerError,
+		Body:       http.StatusText(http.StatusInternalServerError),
+	}, nil
+}
+
+// Similarly add a helper for send responses relating to client errors.
+func clientError(status int) (events.APIGatewayProxyResponse, error) {
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Body:       http.StatusText(status),
+	}, nil
+}
+
+func main() {
+	lambda.Start(issueKey)
+}
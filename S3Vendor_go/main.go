@@ -2,12 +2,15 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/http/httputil"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/github"
@@ -16,10 +19,11 @@ import (
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/apigatewaymanagementapi"
-	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewaymanagementapi"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
 )
 
 var debugLogger = log.New(os.Stderr, "DEBUG ", log.Llongfile)
@@ -46,11 +50,17 @@ func (t *uaSetterTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	return response, err
 }
 
-func convertPolicyARNs(policyARNs []string) []*sts.PolicyDescriptorType {
+// oidcRoleArn is the IAM role assumed via AssumeRoleWithWebIdentity for
+// callers that present a GitHub OIDC ID token. Its trust policy pins the
+// `token.actions.githubusercontent.com` issuer and checks the `sub`/`repo`
+// claims, so holding a valid token is not by itself sufficient to assume it.
+var oidcRoleArn = os.Getenv("OIDC_ROLE_ARN")
+
+func convertPolicyARNs(policyARNs []string) []ststypes.PolicyDescriptorType {
 	size := len(policyARNs)
-	retval := make([]*sts.PolicyDescriptorType, size, size)
+	retval := make([]ststypes.PolicyDescriptorType, size, size)
 	for i, arn := range policyARNs {
-		retval[i] = &sts.PolicyDescriptorType{
+		retval[i] = ststypes.PolicyDescriptorType{
 			Arn: aws.String(arn),
 		}
 	}
@@ -62,6 +72,34 @@ type UserCredentialsResponse struct {
 	AWS_ACCESS_KEY_ID     string
 	AWS_SECRET_ACCESS_KEY string
 	AWS_SESSION_TOKEN     string
+	SSE_ALGORITHM         string
+	SSE_KMS_KEY_ID        string
+	UPLOAD_MODE           string
+}
+
+// Upload modes accepted via the /vendor `mode` query parameter. putUploadMode
+// is the default, scoping the vended credential to a single s3:PutObject.
+// multipartUploadMode additionally authorizes the multipart upload actions
+// needed for rr traces over the 5 GB single-PUT limit.
+const (
+	putUploadMode       = "put"
+	multipartUploadMode = "multipart"
+)
+
+// sseConfig describes the server-side encryption the vended session policy
+// requires the uploader to apply, read from S3_SSE_ALGORITHM ("AES256" or
+// "aws:kms") and, for KMS, S3_SSE_KMS_KEY_ID. Both are empty when the bucket
+// does not enforce SSE.
+type sseConfig struct {
+	Algorithm string
+	KMSKeyID  string
+}
+
+func sseConfigFromEnv() sseConfig {
+	return sseConfig{
+		Algorithm: os.Getenv("S3_SSE_ALGORITHM"),
+		KMSKeyID:  os.Getenv("S3_SSE_KMS_KEY_ID"),
+	}
 }
 
 var ws_mgmt_endpoint = "https://53ly7yebjg.execute-api.us-east-1.amazonaws.com/test"
@@ -103,48 +141,53 @@ func vendor(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse,
 
 	user_name := user.GetName()
 
-	// Get an AWS token for the user
-	os.Setenv("AWS_ACCESS_KEY_ID", os.Getenv("STS_AWS_ACCESS_KEY_ID"))
-	os.Setenv("AWS_SECRET_ACCESS_KEY", os.Getenv("STS_AWS_SECRET_ACCESS_KEY"))
-	os.Unsetenv("AWS_SESSION_TOKEN")
+	// Load the v2 config. This resolves credentials through the standard
+	// provider chain (env vars, shared config/credentials files, IRSA/web
+	// identity, EC2/ECS role, etc), rather than requiring a hard-coded
+	// STS_AWS_* admin keypair.
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return serverError(err)
+	}
+	stsSvc := sts.NewFromConfig(cfg)
 
 	currentTime := time.Now()
 	fname := fmt.Sprintf("reports/%s-%s.tar.gz", currentTime.Format("2006-01-02T15-04-05"), user.GetLogin())
-
-	awsSession := session.New()
-	svc := sts.New(awsSession)
-	tokenInput := &sts.GetFederationTokenInput{
-		DurationSeconds: aws.Int64(60*60),
+	sse := sseConfigFromEnv()
+	mode := putUploadMode
+	if req.QueryStringParameters["mode"] == multipartUploadMode {
+		mode = multipartUploadMode
+	}
+	policy := uploadSessionPolicy(fname, mode, sse)
+
+	var awsCreds *ststypes.Credentials
+	if idToken := req.QueryStringParameters["id_token"]; idToken != "" {
+		repo := req.QueryStringParameters["repo"]
+		if err := verifyOIDCIdentity(idToken, user.GetLogin(), repo); err != nil {
+			errorLogger.Println(err.Error())
+			return clientError(403)
+		}
+		awsCreds, err = assumeRoleWithGithubOIDC(ctx, stsSvc, idToken, user.GetLogin(), repo, policy)
+	} else {
+		awsCreds, err = getFederationToken(ctx, stsSvc, user.GetLogin(), policy)
 	}
-	tokenInput.Name = aws.String(user.GetLogin())
-	PolicyArns := []string{"arn:aws:iam::873569884612:policy/julialang-dumps-upload"}
-	tokenInput.PolicyArns = convertPolicyARNs(PolicyArns)
-	policy := fmt.Sprintf(`{
-		"Version": "2012-10-17",
-		"Statement": [
-			{
-				"Effect": "Allow",
-				"Action": "s3:PutObject",
-				"Resource": "arn:aws:s3:::julialang-dumps/%s"
-			}
-		]
-	}`, fname)
-	tokenInput.Policy = &policy
-
-	tokenOut, err := svc.GetFederationToken(tokenInput)
 	if err != nil {
 		return serverError(err)
 	}
 
 	// Send to the user's WebSocket session
-	wsMgmt := apigatewaymanagementapi.New(awsSession, aws.NewConfig().WithEndpoint(ws_mgmt_endpoint))
+	wsMgmt := apigatewaymanagementapi.NewFromConfig(cfg, func(o *apigatewaymanagementapi.Options) {
+		o.BaseEndpoint = aws.String(ws_mgmt_endpoint)
+	})
 
-	awsCreds := tokenOut.Credentials
 	response := UserCredentialsResponse{
 		UPLOAD_PATH:           fname,
 		AWS_ACCESS_KEY_ID:     *awsCreds.AccessKeyId,
 		AWS_SECRET_ACCESS_KEY: *awsCreds.SecretAccessKey,
 		AWS_SESSION_TOKEN:     *awsCreds.SessionToken,
+		SSE_ALGORITHM:         sse.Algorithm,
+		SSE_KMS_KEY_ID:        sse.KMSKeyID,
+		UPLOAD_MODE:           mode,
 	}
 
 	responseData, err := json.Marshal(response)
@@ -153,7 +196,7 @@ func vendor(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse,
 	}
 
 	debugLogger.Println(fmt.Sprintf("State is %s", state))
-	_, err = wsMgmt.PostToConnection(&apigatewaymanagementapi.PostToConnectionInput{
+	_, err = wsMgmt.PostToConnection(ctx, &apigatewaymanagementapi.PostToConnectionInput{
 		ConnectionId: aws.String(state),
 		Data:         responseData,
 	})
@@ -169,6 +212,158 @@ func vendor(req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse,
 	}, nil
 }
 
+// uploadActions returns the S3 actions the vended session policy should
+// authorize for the given upload mode.
+func uploadActions(mode string) []string {
+	if mode == multipartUploadMode {
+		return []string{
+			"s3:PutObject",
+			"s3:CreateMultipartUpload",
+			"s3:UploadPart",
+			"s3:CompleteMultipartUpload",
+			"s3:AbortMultipartUpload",
+			"s3:ListMultipartUploadParts",
+		}
+	}
+	return []string{"s3:PutObject"}
+}
+
+func quotedActions(actions []string) string {
+	quoted := make([]string, len(actions))
+	for i, action := range actions {
+		quoted[i] = fmt.Sprintf(`"%s"`, action)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// uploadSessionPolicy returns the inline session policy scoping a vended
+// credential to the actions for mode (single-PUT or multipart) against the
+// user's report key. If sse.Algorithm is set, the statement also requires
+// the matching x-amz-server-side-encryption header (and KMS key id, when
+// using "aws:kms"), so the credential cannot be used to write unencrypted
+// objects to a bucket with a RequireEncryption bucket policy.
+func uploadSessionPolicy(fname string, mode string, sse sseConfig) string {
+	condition := ""
+	if sse.Algorithm != "" {
+		condition = fmt.Sprintf(`,
+				"Condition": {
+					"StringEquals": {
+						"s3:x-amz-server-side-encryption": "%s"%s
+					}
+				}`, sse.Algorithm, kmsKeyCondition(sse))
+	}
+	return fmt.Sprintf(`{
+		"Version": "2012-10-17",
+		"Statement": [
+			{
+				"Effect": "Allow",
+				"Action": [%s],
+				"Resource": "arn:aws:s3:::julialang-dumps/%s"%s
+			}
+		]
+	}`, quotedActions(uploadActions(mode)), fname, condition)
+}
+
+func kmsKeyCondition(sse sseConfig) string {
+	if sse.Algorithm != "aws:kms" || sse.KMSKeyID == "" {
+		return ""
+	}
+	return fmt.Sprintf(`,
+						"s3:x-amz-server-side-encryption-aws-kms-key-id": "%s"`, sse.KMSKeyID)
+}
+
+// getFederationToken mints a short-lived federation token for an
+// interactively-authenticated user, scoped by policy to that user's upload
+// key. This is the legacy path, kept for callers with no OIDC token.
+func getFederationToken(ctx context.Context, stsSvc *sts.Client, login string, policy string) (*ststypes.Credentials, error) {
+	tokenInput := &sts.GetFederationTokenInput{
+		DurationSeconds: aws.Int32(60 * 60),
+		Name:            aws.String(login),
+		PolicyArns:      convertPolicyARNs([]string{"arn:aws:iam::873569884612:policy/julialang-dumps-upload"}),
+		Policy:          aws.String(policy),
+	}
+	tokenOut, err := stsSvc.GetFederationToken(ctx, tokenInput)
+	if err != nil {
+		return nil, err
+	}
+	return tokenOut.Credentials, nil
+}
+
+// parseJWTClaims decodes the payload segment of a JWT without verifying its
+// signature, returning the claim set as a generic map. AWS STS independently
+// verifies the token's signature against the configured OIDC provider when
+// AssumeRoleWithWebIdentity is called, so this is only used to read claims
+// for the pre-flight identity check in verifyOIDCIdentity.
+func parseJWTClaims(idToken string) (map[string]interface{}, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("id_token is not a well-formed JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("decoding id_token payload: %w", err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("parsing id_token claims: %w", err)
+	}
+	return claims, nil
+}
+
+// verifyOIDCIdentity checks that the `repo` claim of a GitHub Actions OIDC
+// ID token (format "owner/name") agrees with the repo the caller asked to
+// upload under, and that its owner matches the GitHub login established by
+// the OAuth exchange. Without this check, the report filename and session
+// name are derived from the OAuth identity while the credential actually
+// being minted is scoped to whatever repo the token claims, letting a
+// caller mint a session under their own login while presenting a
+// differently-owned repo's token.
+func verifyOIDCIdentity(idToken string, login string, repo string) error {
+	claims, err := parseJWTClaims(idToken)
+	if err != nil {
+		return err
+	}
+	tokenRepo, _ := claims["repo"].(string)
+	if tokenRepo == "" {
+		return errors.New("id_token has no repo claim")
+	}
+	if repo != "" && !strings.EqualFold(tokenRepo, repo) {
+		return fmt.Errorf("id_token repo claim %q does not match requested repo %q", tokenRepo, repo)
+	}
+	owner := tokenRepo
+	if idx := strings.Index(tokenRepo, "/"); idx >= 0 {
+		owner = tokenRepo[:idx]
+	}
+	if !strings.EqualFold(owner, login) {
+		return fmt.Errorf("id_token repo owner %q does not match authenticated GitHub login %q", owner, login)
+	}
+	return nil
+}
+
+// assumeRoleWithGithubOIDC exchanges a GitHub OIDC ID token for short-lived
+// credentials via AssumeRoleWithWebIdentity. The target role's trust policy
+// pins the `token.actions.githubusercontent.com` issuer and checks the
+// token's `sub`/`repo` claim, so the Lambda itself never needs to hold a
+// long-lived AWS secret to vend credentials for CI callers.
+func assumeRoleWithGithubOIDC(ctx context.Context, stsSvc *sts.Client, idToken string, login string, repo string, policy string) (*ststypes.Credentials, error) {
+	sessionName := login
+	if repo != "" {
+		sessionName = fmt.Sprintf("%s@%s", login, repo)
+	}
+	assumeInput := &sts.AssumeRoleWithWebIdentityInput{
+		RoleArn:          aws.String(oidcRoleArn),
+		RoleSessionName:  aws.String(sessionName),
+		WebIdentityToken: aws.String(idToken),
+		DurationSeconds:  aws.Int32(60 * 60),
+		Policy:           aws.String(policy),
+	}
+	assumeOut, err := stsSvc.AssumeRoleWithWebIdentity(ctx, assumeInput)
+	if err != nil {
+		return nil, err
+	}
+	return assumeOut.Credentials, nil
+}
+
 // Add a helper for handling errors. This logs any error to os.Stderr
 // and returns a 500 Internal Server Error response that the AWS API
 // Gateway understands.
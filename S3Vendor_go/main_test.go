@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// policyDocument and parsePolicy are shared scaffolding for the
+// uploadSessionPolicy tests in sse_policy_test.go and multipart_test.go.
+type policyDocument struct {
+	Statement []struct {
+		Action    []string
+		Resource  string
+		Condition map[string]map[string]string
+	}
+}
+
+func parsePolicy(t *testing.T, policy string) policyDocument {
+	t.Helper()
+	var doc policyDocument
+	if err := json.Unmarshal([]byte(policy), &doc); err != nil {
+		t.Fatalf("uploadSessionPolicy produced invalid JSON: %v\npolicy: %s", err, policy)
+	}
+	if len(doc.Statement) != 1 {
+		t.Fatalf("policy has %d statements, want 1", len(doc.Statement))
+	}
+	return doc
+}
@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestUploadActions(t *testing.T) {
+	put := uploadActions(putUploadMode)
+	if len(put) != 1 || put[0] != "s3:PutObject" {
+		t.Fatalf("putUploadMode actions = %v, want [s3:PutObject]", put)
+	}
+
+	multipart := uploadActions(multipartUploadMode)
+	want := []string{
+		"s3:PutObject",
+		"s3:CreateMultipartUpload",
+		"s3:UploadPart",
+		"s3:CompleteMultipartUpload",
+		"s3:AbortMultipartUpload",
+		"s3:ListMultipartUploadParts",
+	}
+	if len(multipart) != len(want) {
+		t.Fatalf("multipartUploadMode actions = %v, want %v", multipart, want)
+	}
+	for i, action := range want {
+		if multipart[i] != action {
+			t.Errorf("multipartUploadMode actions[%d] = %q, want %q", i, multipart[i], action)
+		}
+	}
+}
+
+func TestUploadSessionPolicyMultipartActions(t *testing.T) {
+	policy := uploadSessionPolicy("reports/foo.tar.gz", multipartUploadMode, sseConfig{})
+	doc := parsePolicy(t, policy)
+	stmt := doc.Statement[0]
+
+	want := uploadActions(multipartUploadMode)
+	if len(stmt.Action) != len(want) {
+		t.Fatalf("Action = %v, want %v", stmt.Action, want)
+	}
+	for i, action := range want {
+		if stmt.Action[i] != action {
+			t.Errorf("Action[%d] = %q, want %q", i, stmt.Action[i], action)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// makeUnsignedJWT builds a JWT with the given claims and an empty
+// signature segment; verifyOIDCIdentity only reads the payload, so the
+// (unchecked here) signature can be a placeholder.
+func makeUnsignedJWT(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshaling claims: %v", err)
+	}
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return strings.Join([]string{header, body, "sig"}, ".")
+}
+
+func TestVerifyOIDCIdentityMatches(t *testing.T) {
+	token := makeUnsignedJWT(t, map[string]interface{}{"repo": "keno/julia-reports"})
+	if err := verifyOIDCIdentity(token, "keno", "keno/julia-reports"); err != nil {
+		t.Errorf("verifyOIDCIdentity() = %v, want nil", err)
+	}
+}
+
+func TestVerifyOIDCIdentityNoRepoParam(t *testing.T) {
+	token := makeUnsignedJWT(t, map[string]interface{}{"repo": "keno/julia-reports"})
+	if err := verifyOIDCIdentity(token, "keno", ""); err != nil {
+		t.Errorf("verifyOIDCIdentity() = %v, want nil when caller did not pass repo", err)
+	}
+}
+
+func TestVerifyOIDCIdentityRepoMismatch(t *testing.T) {
+	token := makeUnsignedJWT(t, map[string]interface{}{"repo": "keno/julia-reports"})
+	if err := verifyOIDCIdentity(token, "keno", "someoneelse/other-repo"); err == nil {
+		t.Error("verifyOIDCIdentity() = nil, want error on repo claim mismatch")
+	}
+}
+
+func TestVerifyOIDCIdentityOwnerMismatch(t *testing.T) {
+	token := makeUnsignedJWT(t, map[string]interface{}{"repo": "someoneelse/julia-reports"})
+	if err := verifyOIDCIdentity(token, "keno", "someoneelse/julia-reports"); err == nil {
+		t.Error("verifyOIDCIdentity() = nil, want error when token repo owner differs from OAuth login")
+	}
+}
+
+func TestVerifyOIDCIdentityMissingRepoClaim(t *testing.T) {
+	token := makeUnsignedJWT(t, map[string]interface{}{})
+	if err := verifyOIDCIdentity(token, "keno", ""); err == nil {
+		t.Error("verifyOIDCIdentity() = nil, want error when id_token has no repo claim")
+	}
+}
+
+func TestVerifyOIDCIdentityMalformedToken(t *testing.T) {
+	if err := verifyOIDCIdentity("not-a-jwt", "keno", ""); err == nil {
+		t.Error("verifyOIDCIdentity() = nil, want error for a malformed id_token")
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestUploadSessionPolicyNoSSE(t *testing.T) {
+	policy := uploadSessionPolicy("reports/foo.tar.gz", putUploadMode, sseConfig{})
+	doc := parsePolicy(t, policy)
+	stmt := doc.Statement[0]
+
+	if want := "arn:aws:s3:::julialang-dumps/reports/foo.tar.gz"; stmt.Resource != want {
+		t.Errorf("Resource = %q, want %q", stmt.Resource, want)
+	}
+	if len(stmt.Action) != 1 || stmt.Action[0] != "s3:PutObject" {
+		t.Errorf("Action = %v, want [s3:PutObject]", stmt.Action)
+	}
+	if stmt.Condition != nil {
+		t.Errorf("Condition = %v, want none when no SSE is configured", stmt.Condition)
+	}
+}
+
+func TestUploadSessionPolicyAES256(t *testing.T) {
+	policy := uploadSessionPolicy("reports/foo.tar.gz", putUploadMode, sseConfig{Algorithm: "AES256"})
+	doc := parsePolicy(t, policy)
+	stmt := doc.Statement[0]
+
+	cond, ok := stmt.Condition["StringEquals"]
+	if !ok {
+		t.Fatalf("Condition = %v, want a StringEquals block", stmt.Condition)
+	}
+	if cond["s3:x-amz-server-side-encryption"] != "AES256" {
+		t.Errorf("s3:x-amz-server-side-encryption = %q, want AES256", cond["s3:x-amz-server-side-encryption"])
+	}
+	if _, ok := cond["s3:x-amz-server-side-encryption-aws-kms-key-id"]; ok {
+		t.Errorf("AES256 policy should not require a KMS key id condition")
+	}
+}
+
+func TestUploadSessionPolicyKMS(t *testing.T) {
+	sse := sseConfig{Algorithm: "aws:kms", KMSKeyID: "arn:aws:kms:us-east-1:873569884612:key/test-key"}
+	policy := uploadSessionPolicy("reports/foo.tar.gz", putUploadMode, sse)
+	doc := parsePolicy(t, policy)
+	stmt := doc.Statement[0]
+
+	cond := stmt.Condition["StringEquals"]
+	if cond["s3:x-amz-server-side-encryption"] != "aws:kms" {
+		t.Errorf("s3:x-amz-server-side-encryption = %q, want aws:kms", cond["s3:x-amz-server-side-encryption"])
+	}
+	if cond["s3:x-amz-server-side-encryption-aws-kms-key-id"] != sse.KMSKeyID {
+		t.Errorf("s3:x-amz-server-side-encryption-aws-kms-key-id = %q, want %q", cond["s3:x-amz-server-side-encryption-aws-kms-key-id"], sse.KMSKeyID)
+	}
+}